@@ -0,0 +1,84 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	tcmongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+// Spins up a disposable Mongo instance, seeds it with a join/leave sequence
+// that includes a duplicate leave beacon, and exercises the real attendance
+// aggregation pipeline end to end.
+func TestRunAttendanceAggregation(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := tcmongodb.Run(ctx, "mongo:6")
+	if err != nil {
+		t.Fatalf("failed to start mongo container: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Logf("failed to terminate mongo container: %v", err)
+		}
+	})
+
+	endpoint, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("failed to get connection string: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(endpoint))
+	if err != nil {
+		t.Fatalf("failed to connect to mongo: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect(ctx) })
+
+	mongoClient = client
+	DATABASE = "jitsi_test"
+	COLLECTION = "logs"
+
+	collection := client.Database(DATABASE).Collection(COLLECTION)
+	base := time.Date(2026, 7, 1, 10, 0, 0, 0, time.UTC)
+	docs := []interface{}{
+		Jitsilog{Turma: "turma-a", Jid: "jid-1", Timestamp: base.Format(time.RFC3339), Action: "join"},
+		Jitsilog{Turma: "turma-a", Jid: "jid-1", Timestamp: base.Add(5 * time.Minute).Format(time.RFC3339), Action: "leave"},
+		// Duplicate leave beacon for the same jid: must not pair again against
+		// the already-consumed join timestamp (regression for the
+		// chunk0-1 duration-pairing fix).
+		Jitsilog{Turma: "turma-a", Jid: "jid-1", Timestamp: base.Add(6 * time.Minute).Format(time.RFC3339), Action: "leave"},
+	}
+	if _, err := collection.InsertMany(ctx, docs); err != nil {
+		t.Fatalf("failed to seed documents: %v", err)
+	}
+
+	pipeline, err := buildAttendancePipeline("turma", "", "", "day")
+	if err != nil {
+		t.Fatalf("buildAttendancePipeline: %v", err)
+	}
+
+	err, records := runAttendanceAggregation(ctx, pipeline)
+	if err != nil {
+		t.Fatalf("runAttendanceAggregation: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 bucket/group record, got %d", len(records))
+	}
+
+	record := records[0]
+	if _, err := time.Parse(time.RFC3339, record.Bucket); err != nil {
+		t.Fatalf("bucket %q did not decode as an RFC3339 string: %v", record.Bucket, err)
+	}
+	if record.Joins != 1 || record.Leaves != 2 {
+		t.Fatalf("expected 1 join / 2 leaves, got %d/%d", record.Joins, record.Leaves)
+	}
+	if record.AvgDurationSeconds != 300 {
+		t.Fatalf("expected the duplicate leave to be ignored (300s avg dwell), got %v", record.AvgDurationSeconds)
+	}
+}
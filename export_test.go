@@ -0,0 +1,121 @@
+//go:build integration
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	tcmongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+// slowReader throttles reads to simulate a client pulling the response body
+// over a slow pipe, one line at a time.
+type slowReader struct {
+	r     io.Reader
+	delay time.Duration
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	return s.r.Read(p)
+}
+
+// Verifies that NDJSON export streams records to a slow consumer as they are
+// decoded instead of buffering the whole result set in memory first: the
+// first line must arrive long before the export as a whole completes.
+func TestSearchAndExportAsNDJSONSlowConsumer(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := tcmongodb.Run(ctx, "mongo:6")
+	if err != nil {
+		t.Fatalf("failed to start mongo container: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Logf("failed to terminate mongo container: %v", err)
+		}
+	})
+
+	endpoint, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("failed to get connection string: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(endpoint))
+	if err != nil {
+		t.Fatalf("failed to connect to mongo: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect(ctx) })
+
+	mongoClient = client
+	DATABASE = "jitsi_test"
+	COLLECTION = "logs"
+	TIMEZONE = "UTC"
+	BATCH_SIZE = 5
+
+	collection := client.Database(DATABASE).Collection(COLLECTION)
+	base := time.Date(2026, 7, 1, 10, 0, 0, 0, time.UTC)
+	docs := make([]interface{}, 0, 50)
+	for i := 0; i < 50; i++ {
+		docs = append(docs, Jitsilog{
+			Jid:       "jid-1",
+			Timestamp: base.Add(time.Duration(i) * time.Second).Format(time.RFC3339),
+			Action:    "join",
+		})
+	}
+	if _, err := collection.InsertMany(ctx, docs); err != nil {
+		t.Fatalf("failed to seed documents: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(searchAndExportAsNDJSON))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"?ts="+base.Add(-time.Minute).Format(time.RFC3339), nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(&slowReader{r: resp.Body, delay: 20 * time.Millisecond})
+	if _, err := reader.ReadString('\n'); err != nil {
+		t.Fatalf("failed to read first line: %v", err)
+	}
+	firstLineAt := time.Since(start)
+
+	lines := 1
+	for {
+		if _, err := reader.ReadString('\n'); err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatalf("failed reading export stream: %v", err)
+		}
+		lines++
+	}
+	total := time.Since(start)
+
+	if lines != len(docs) {
+		t.Fatalf("expected %d exported lines, got %d", len(docs), lines)
+	}
+	// A slow consumer reading one line every 20ms should see the first line
+	// well before the rest of the 50-line export finishes, proving the
+	// handler flushes incrementally rather than writing the whole body at once.
+	if firstLineAt >= total/2 {
+		t.Fatalf("first line arrived at %v, not meaningfully before total duration %v; export does not appear to stream", firstLineAt, total)
+	}
+}
@@ -2,25 +2,50 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Pool and timeout tuning for the shared Mongo client.
+const (
+	mongoMaxPoolSize            = 100
+	mongoMinPoolSize            = 10
+	mongoMaxConnIdleTime        = 5 * time.Minute
+	mongoServerSelectionTimeout = 10 * time.Second
+	mongoHealthCheckInterval    = 30 * time.Second
+	requestTimeout              = 10 * time.Second
 )
 
 var (
+	// Shared, pooled Mongo client, connected once in main and reused by every
+	// request instead of dialing a fresh connection per call.
+	mongoClient *mongo.Client
+
 	// URI for MongoDB connection.
 	URI_MONGODB string
 
@@ -35,18 +60,22 @@ var (
 
 	// Timezone to display datetime.
 	TIMEZONE string
+
+	// Batch size used when streaming cursors out to CSV/NDJSON exports.
+	BATCH_SIZE int64
 )
 
 // Data structure as defined in https://github.com/bryanasdev000/microservice-jitsi-log .
 type Jitsilog struct {
-	Sala      string `json:"sala"`
-	Curso     string `json:"curso"`
-	Turma     string `json:"turma"`
-	Aluno     string `json:"aluno"`
-	Jid       string `json:"jid"`
-	Email     string `json:"email"`
-	Timestamp string `json:"timestamp"`
-	Action    string `json:"action"`
+	Id        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Sala      string             `json:"sala"`
+	Curso     string             `json:"curso"`
+	Turma     string             `json:"turma"`
+	Aluno     string             `json:"aluno"`
+	Jid       string             `json:"jid"`
+	Email     string             `json:"email"`
+	Timestamp string             `json:"timestamp"`
+	Action    string             `json:"action"`
 }
 
 func cabecalhoCSV() (c []string) {
@@ -110,6 +139,18 @@ func init() {
 		PORT = ":8080"
 		log.Info("Port variable is missing or in wrong format (missing a colon ( : ) at start. It should be like ':8080'), using default: :8080")
 	}
+	if batchSize, found := os.LookupEnv("BATCH_SIZE"); found {
+		parsed, err := strconv.ParseInt(batchSize, 10, 64)
+		if err != nil || parsed <= 0 {
+			log.WithFields(log.Fields{
+				"error": err}).Info("Invalid BATCH_SIZE, using default: 500")
+			BATCH_SIZE = 500
+		} else {
+			BATCH_SIZE = parsed
+		}
+	} else {
+		BATCH_SIZE = 500
+	}
 	log.WithFields(log.Fields{
 		"URI":        URI_MONGODB,
 		"Database":   DATABASE,
@@ -119,25 +160,357 @@ func init() {
 	log.Info("CORS Enabled")
 }
 
-// Creates and return a MongoDB client.
-func getClient() *mongo.Client {
-	context, _ := context.WithTimeout(context.Background(), 10*time.Second)
-	client, err := mongo.Connect(context, options.Client().ApplyURI(URI_MONGODB))
+// Prometheus metrics exposed at /metrics.
+var (
+	httpRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Total HTTP requests processed, partitioned by route, method and status.",
+		},
+		[]string{"route", "method", "status"},
+	)
+	httpRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency in seconds.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"route", "method", "status"},
+	)
+	mongoQueryDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "mongo_query_duration_seconds",
+			Help:    "Mongo command latency in seconds.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"op", "collection"},
+	)
+	mongoDocsReturned = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mongo_docs_returned",
+			Help: "Documents returned by Mongo find/aggregate commands.",
+		},
+		[]string{"op", "collection"},
+	)
+	mongoPoolInUse = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "mongo_pool_connections_in_use",
+			Help: "Mongo connections currently checked out of the pool.",
+		},
+	)
+	mongoPoolIdle = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "mongo_pool_connections_idle",
+			Help: "Mongo connections idle in the pool.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		httpRequestsTotal,
+		httpRequestDuration,
+		mongoQueryDuration,
+		mongoDocsReturned,
+		mongoPoolInUse,
+		mongoPoolIdle,
+	)
+}
+
+// Counts the documents carried by a Mongo command reply, for find/aggregate
+// (first batch) and write commands (the "n" field) alike.
+func countReplyDocuments(reply bson.Raw) int64 {
+	if cursorVal, err := reply.LookupErr("cursor", "firstBatch"); err == nil {
+		if arr, ok := cursorVal.ArrayOK(); ok {
+			if values, err := arr.Values(); err == nil {
+				return int64(len(values))
+			}
+		}
+	}
+	if nVal, err := reply.LookupErr("n"); err == nil {
+		if n, ok := nVal.Int32OK(); ok {
+			return int64(n)
+		}
+	}
+	return 0
+}
+
+// Wraps otelmongo's command monitor (for tracing) with our own Prometheus
+// recording of mongo_query_duration_seconds and mongo_docs_returned.
+func newMongoCommandMonitor() *event.CommandMonitor {
+	otelMonitor := otelmongo.NewMonitor()
+	var mu sync.Mutex
+	started := map[int64]time.Time{}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			otelMonitor.Started(ctx, evt)
+			mu.Lock()
+			started[evt.RequestID] = time.Now()
+			mu.Unlock()
+		},
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			otelMonitor.Succeeded(ctx, evt)
+			mu.Lock()
+			start, ok := started[evt.RequestID]
+			delete(started, evt.RequestID)
+			mu.Unlock()
+			if ok {
+				mongoQueryDuration.WithLabelValues(evt.CommandName, COLLECTION).Observe(time.Since(start).Seconds())
+			}
+			mongoDocsReturned.WithLabelValues(evt.CommandName, COLLECTION).Add(float64(countReplyDocuments(evt.Reply)))
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			otelMonitor.Failed(ctx, evt)
+			mu.Lock()
+			start, ok := started[evt.RequestID]
+			delete(started, evt.RequestID)
+			mu.Unlock()
+			if ok {
+				mongoQueryDuration.WithLabelValues(evt.CommandName, COLLECTION).Observe(time.Since(start).Seconds())
+			}
+		},
+	}
+}
+
+// Tracks connection pool in-use/idle gauges from driver pool events.
+func newMongoPoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(evt *event.PoolEvent) {
+			switch evt.Type {
+			case event.GetSucceeded:
+				mongoPoolInUse.Inc()
+				mongoPoolIdle.Dec()
+			case event.ConnectionReturned:
+				mongoPoolInUse.Dec()
+				mongoPoolIdle.Inc()
+			case event.ConnectionCreated:
+				mongoPoolIdle.Inc()
+			case event.ConnectionClosed:
+				mongoPoolIdle.Dec()
+			}
+		},
+	}
+}
+
+// Connects the shared, pooled Mongo client used by every handler.
+func connectMongo() (*mongo.Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoServerSelectionTimeout)
+	defer cancel()
+	clientOpts := options.Client().
+		ApplyURI(URI_MONGODB).
+		SetMaxPoolSize(mongoMaxPoolSize).
+		SetMinPoolSize(mongoMinPoolSize).
+		SetMaxConnIdleTime(mongoMaxConnIdleTime).
+		SetServerSelectionTimeout(mongoServerSelectionTimeout).
+		SetMonitor(newMongoCommandMonitor()).
+		SetPoolMonitor(newMongoPoolMonitor())
+	client, err := mongo.Connect(ctx, clientOpts)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"error": err}).Fatal("Failed to create the Mongo client!")
+		return nil, err
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// Periodically pings Mongo so connection issues show up in the logs instead
+// of only surfacing as a failed request. Stops when stop is closed.
+func mongoHealthLoop(client *mongo.Client, stop <-chan struct{}) {
+	ticker := time.NewTicker(mongoHealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), mongoServerSelectionTimeout)
+			if err := client.Ping(ctx, nil); err != nil {
+				log.WithFields(log.Fields{
+					"error": err}).Warn("Mongo health ping failed")
+			}
+			cancel()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Encodes a keyset pagination cursor from the (raw) timestamp and id of the
+// last row of a page.
+func encodeCursor(timestamp string, id primitive.ObjectID) string {
+	return timestamp + "_" + id.Hex()
+}
+
+// Decodes a keyset pagination cursor produced by encodeCursor.
+func decodeCursor(after string) (string, primitive.ObjectID, error) {
+	parts := strings.SplitN(after, "_", 2)
+	if len(parts) != 2 {
+		return "", primitive.NilObjectID, fmt.Errorf("invalid after cursor: %s", after)
+	}
+	id, err := primitive.ObjectIDFromHex(parts[1])
+	if err != nil {
+		return "", primitive.NilObjectID, fmt.Errorf("invalid after cursor id: %w", err)
+	}
+	return parts[0], id, nil
+}
+
+// Supported operators in the search DSL.
+const (
+	predOpEq       = "eq"
+	predOpContains = "contains"
+	predOpIn       = "in"
+	predOpBetween  = "between"
+
+	// Caps the number of predicates a single request can combine, so a
+	// deeply nested filter can't force an expensive query plan.
+	maxDSLPredicates = 20
+)
+
+// Fields that may be queried through the search DSL and the export filter param.
+var queryableFields = map[string]bool{
+	"sala":      true,
+	"curso":     true,
+	"turma":     true,
+	"aluno":     true,
+	"jid":       true,
+	"email":     true,
+	"timestamp": true,
+	"action":    true,
+}
+
+// A single field predicate in the search DSL.
+type Predicate struct {
+	Field  string   `json:"field"`
+	Op     string   `json:"op"`
+	Value  string   `json:"value,omitempty"`
+	Values []string `json:"values,omitempty"`
+	From   string   `json:"from,omitempty"`
+	To     string   `json:"to,omitempty"`
+	// Raw disables regex-metacharacter escaping on a "contains" predicate.
+	// Only honored when the request is authorized via API_KEY.
+	Raw bool `json:"raw,omitempty"`
+}
+
+// A compound group of predicates and/or nested groups, combined with Op
+// ("and", the default, or "or").
+type FilterGroup struct {
+	Op         string        `json:"op,omitempty"`
+	Predicates []Predicate   `json:"predicates,omitempty"`
+	Groups     []FilterGroup `json:"groups,omitempty"`
+}
+
+// Checks whether the request is authorized to use raw (unescaped) regex
+// patterns in "contains" predicates.
+func rawModeAllowed(r *http.Request) bool {
+	apiKey := os.Getenv("API_KEY")
+	if apiKey == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Api-Key")), []byte(apiKey)) == 1
+}
+
+// Translates a single predicate into its bson condition, escaping regex
+// metacharacters on "contains" unless raw mode was requested and authorized.
+func buildPredicate(p Predicate, rawAllowed bool, predicateCount *int) (bson.D, error) {
+	if !queryableFields[p.Field] {
+		return nil, fmt.Errorf("field %q is not queryable", p.Field)
+	}
+	*predicateCount++
+	if *predicateCount > maxDSLPredicates {
+		return nil, fmt.Errorf("too many predicates, max %d", maxDSLPredicates)
+	}
+
+	switch p.Op {
+	case predOpEq:
+		return bson.D{{Key: p.Field, Value: p.Value}}, nil
+	case predOpContains:
+		pattern := p.Value
+		if !(p.Raw && rawAllowed) {
+			pattern = regexp.QuoteMeta(pattern)
+		}
+		return bson.D{{Key: p.Field, Value: bson.D{{Key: "$regex", Value: primitive.Regex{Pattern: pattern, Options: "i"}}}}}, nil
+	case predOpIn:
+		values := make(bson.A, 0, len(p.Values))
+		for _, v := range p.Values {
+			values = append(values, v)
+		}
+		return bson.D{{Key: p.Field, Value: bson.D{{Key: "$in", Value: values}}}}, nil
+	case predOpBetween:
+		if p.Field != "timestamp" {
+			return nil, fmt.Errorf("between is only supported on the timestamp field")
+		}
+		rangeFilter := bson.D{}
+		if p.From != "" {
+			rangeFilter = append(rangeFilter, bson.E{Key: "$gte", Value: p.From})
+		}
+		if p.To != "" {
+			rangeFilter = append(rangeFilter, bson.E{Key: "$lte", Value: p.To})
+		}
+		return bson.D{{Key: p.Field, Value: rangeFilter}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported predicate op: %s", p.Op)
+	}
+}
+
+// Recursively translates a FilterGroup into a single validated bson.D,
+// combining predicates and nested groups with $and/$or.
+func buildDSLFilter(group FilterGroup, rawAllowed bool, predicateCount *int) (bson.D, error) {
+	op := "$and"
+	if strings.ToLower(group.Op) == "or" {
+		op = "$or"
 	}
-	return client
+
+	var clauses bson.A
+	for _, p := range group.Predicates {
+		clause, err := buildPredicate(p, rawAllowed, predicateCount)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, clause)
+	}
+	for _, g := range group.Groups {
+		clause, err := buildDSLFilter(g, rawAllowed, predicateCount)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, clause)
+	}
+
+	if len(clauses) == 0 {
+		return bson.D{}, nil
+	}
+	if len(clauses) == 1 {
+		return clauses[0].(bson.D), nil
+	}
+	return bson.D{{Key: op, Value: clauses}}, nil
+}
+
+// Parses the optional "filter" query param (a JSON-encoded FilterGroup) used
+// by the CSV/NDJSON export handlers to reuse the search DSL.
+func parseFilterParam(r *http.Request) (bson.D, error) {
+	raw := r.URL.Query().Get("filter")
+	if raw == "" {
+		return bson.D{}, nil
+	}
+	var group FilterGroup
+	if err := json.Unmarshal([]byte(raw), &group); err != nil {
+		return nil, fmt.Errorf("invalid filter param: %w", err)
+	}
+	predicateCount := 0
+	return buildDSLFilter(group, rawModeAllowed(r), &predicateCount)
 }
 
-// Find logs with filter and ordered by decrescent timestamp, can limit & skip items in dataset.
-func findLogsFilter(size string, filter bson.D, skip string) (error, []*Jitsilog) {
+// Find logs with filter and ordered by decrescent timestamp, can limit items in
+// dataset. Pagination is keyset-based: pass the cursor returned as nextCursor
+// in the "after" argument to fetch the following page instead of MongoDB's
+// expensive $skip, which gets slower the deeper it goes. ctx should carry a
+// request-scoped timeout.
+func findLogsFilter(ctx context.Context, size string, filter bson.D, after string) (error, []*Jitsilog, string) {
 	tz, err := time.LoadLocation(TIMEZONE)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error": err}).Fatal("Failed to load TZ info")
 	}
-	client := getClient()
 	optFind := options.Find()
 	var jitsilogs []*Jitsilog
 
@@ -145,51 +518,51 @@ func findLogsFilter(size string, filter bson.D, skip string) (error, []*Jitsilog
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error": err}).Info("Failed to convert size argument to int")
-		return err, nil
-	}
-
-	skipInt, err := strconv.ParseInt(skip, 10, 64)
-	if err != nil {
-		log.WithFields(log.Fields{
-			"error": err}).Info("Failed to convert skip argument to int")
-		return err, nil
-	}
-	log.Debug("Dataset row limit ", sizeInt)
-	log.Debug("Dataset row skip ", skipInt)
-	collection := client.Database(DATABASE).Collection(COLLECTION)
-	count, err := collection.CountDocuments(context.TODO(), filter)
-	if err != nil {
-		log.WithFields(log.Fields{
-			"error": err}).Info("Error on count of the documents")
-		return err, nil
-	}
-	if skipInt > count {
-		skipInt = count
-	} else if skipInt < 0 {
-		skipInt = 0
+		return err, nil, ""
 	}
 	if sizeInt < 0 {
 		sizeInt = 20
 	}
-	log.Debug("Dataset row max: ", count)
-	optFind.SetSkip(skipInt)
+	log.Debug("Dataset row limit ", sizeInt)
+
+	if after != "" {
+		afterTs, afterId, err := decodeCursor(after)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err}).Info("Failed to decode after cursor")
+			return err, nil, ""
+		}
+		cursorFilter := bson.D{{"$or", bson.A{
+			bson.D{{"timestamp", bson.D{{"$lt", afterTs}}}},
+			bson.D{{"timestamp", afterTs}, {"_id", bson.D{{"$lt", afterId}}}},
+		}}}
+		if len(filter) > 0 {
+			filter = bson.D{{"$and", bson.A{filter, cursorFilter}}}
+		} else {
+			filter = cursorFilter
+		}
+	}
+
+	collection := mongoClient.Database(DATABASE).Collection(COLLECTION)
 	optFind.SetLimit(sizeInt)
-	optFind.SetSort(bson.D{{"timestamp", -1}})
-	cursor, err := collection.Find(context.TODO(), filter, optFind)
+	optFind.SetSort(bson.D{{"timestamp", -1}, {"_id", -1}})
+	cursor, err := collection.Find(ctx, filter, optFind)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error": err}).Info("Error on finding the documents")
-		return err, nil
+		return err, nil, ""
 	}
-	log.Debug("Connection to MongoDB opened.")
-	for cursor.Next(context.TODO()) {
+	defer cursor.Close(ctx)
+	var lastRawTimestamp string
+	for cursor.Next(ctx) {
 		var jitsilog Jitsilog
 		err = cursor.Decode(&jitsilog)
 		if err != nil {
 			log.WithFields(log.Fields{
 				"error": err}).Info("Error on decoding the document")
-			return err, nil
+			return err, nil, ""
 		}
+		lastRawTimestamp = jitsilog.Timestamp
 		t, err := time.ParseInLocation(time.RFC3339, jitsilog.Timestamp, tz)
 		if err != nil {
 			log.WithFields(log.Fields{
@@ -200,14 +573,16 @@ func findLogsFilter(size string, filter bson.D, skip string) (error, []*Jitsilog
 		}
 		jitsilogs = append(jitsilogs, &jitsilog)
 	}
+	if err := cursor.Err(); err != nil {
+		return err, nil, ""
+	}
 	log.Debug("Data retrived")
-	err = client.Disconnect(context.TODO())
-	if err != nil {
-		log.WithFields(log.Fields{
-			"error": err}).Fatal("Failed to disconnect from database!")
+
+	var nextCursor string
+	if int64(len(jitsilogs)) == sizeInt && len(jitsilogs) > 0 {
+		nextCursor = encodeCursor(lastRawTimestamp, jitsilogs[len(jitsilogs)-1].Id)
 	}
-	log.Debug("Connection to MongoDB closed.")
-	return nil, jitsilogs
+	return nil, jitsilogs, nextCursor
 }
 
 // Default handler, return the name of this service.
@@ -215,131 +590,618 @@ func defaultHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "microservice-jitsi-log-view")
 }
 
-// Check health of the microservice. Returns the hostname of the machine or container running on.
+// Check health of the microservice. Returns the hostname of the machine or
+// container running on, and pings Mongo to confirm the pool is usable.
 func checkHealth(w http.ResponseWriter, r *http.Request) {
 	name, err := os.Hostname()
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error": err}).Fatal("Failed to get hostname!")
 	}
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+	if err := mongoClient.Ping(ctx, nil); err != nil {
+		log.WithFields(log.Fields{
+			"error": err}).Info("Mongo health check failed")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, "Awake but Mongo is unreachable from %s: %s", name, err.Error())
+		return
+	}
 	fmt.Fprintf(w, "Awake and alive from %s", name)
 }
 
+// Derives a request-scoped context bounded by requestTimeout from r.
+func requestContext(r *http.Request) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(r.Context(), requestTimeout)
+}
+
+// Writes a logs page as JSON, exposing the keyset pagination cursor for the
+// next page (if any) as a response header.
+func writeLogsResponse(w http.ResponseWriter, jitsilogs []*Jitsilog, nextCursor string) {
+	if nextCursor != "" {
+		w.Header().Set("X-Next-Cursor", nextCursor)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jitsilogs)
+}
+
 // Query the latest logs with a variable dataset size based on the URL.
 func latestLogsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
 	queryParams := r.URL.Query()
-	err, jitsilogs := findLogsFilter(queryParams["size"][0], bson.D{}, queryParams["skip"][0])
+	err, jitsilogs, nextCursor := findLogsFilter(ctx, queryParams["size"][0], bson.D{}, queryParams.Get("after"))
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error": err}).Info("Failed to get logs!")
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(jitsilogs)
+	writeLogsResponse(w, jitsilogs, nextCursor)
 }
 
 // Query all logs that correspond with desired courseid.
 func searchCourseHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
 	queryParams := r.URL.Query()
 	filter := bson.D{{}}
 	filter = append(filter, bson.E{Key: "curso", Value: bson.D{{"$regex", primitive.Regex{Pattern: queryParams["id"][0], Options: "gi"}}}})
-	err, jitsilogs := findLogsFilter(queryParams["size"][0], filter, queryParams["skip"][0])
+	err, jitsilogs, nextCursor := findLogsFilter(ctx, queryParams["size"][0], filter, queryParams.Get("after"))
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error": err}).Info("Failed to get logs!")
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(jitsilogs)
+	writeLogsResponse(w, jitsilogs, nextCursor)
 }
 
 // Query all logs that correspond with desired classid
 func searchClassHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
 	queryParams := r.URL.Query()
 	filter := bson.D{{}}
 	filter = append(filter, bson.E{Key: "turma", Value: bson.D{{"$regex", primitive.Regex{Pattern: queryParams["id"][0], Options: "gi"}}}})
-	err, jitsilogs := findLogsFilter(queryParams["size"][0], filter, queryParams["skip"][0])
+	err, jitsilogs, nextCursor := findLogsFilter(ctx, queryParams["size"][0], filter, queryParams.Get("after"))
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error": err}).Info("Failed to get logs!")
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(jitsilogs)
+	writeLogsResponse(w, jitsilogs, nextCursor)
 }
 
 // Query all logs that correspond with desired roomid
 func searchRoomHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
 	queryParams := r.URL.Query()
 	filter := bson.D{{}}
 	filter = append(filter, bson.E{Key: "sala", Value: bson.D{{"$regex", primitive.Regex{Pattern: queryParams["id"][0], Options: "gi"}}}})
-	err, jitsilogs := findLogsFilter(queryParams["size"][0], filter, queryParams["skip"][0])
+	err, jitsilogs, nextCursor := findLogsFilter(ctx, queryParams["size"][0], filter, queryParams.Get("after"))
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error": err}).Info("Failed to get logs!")
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(jitsilogs)
+	writeLogsResponse(w, jitsilogs, nextCursor)
 }
 
 // Query all logs that correspond with desired student email
 func searchStudentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
 	queryParams := r.URL.Query()
 	filter := bson.D{{}}
 	filter = append(filter, bson.E{Key: "email", Value: bson.D{{"$regex", primitive.Regex{Pattern: queryParams["email"][0], Options: "gi"}}}})
-	err, jitsilogs := findLogsFilter(queryParams["size"][0], filter, queryParams["skip"][0])
+	err, jitsilogs, nextCursor := findLogsFilter(ctx, queryParams["size"][0], filter, queryParams.Get("after"))
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error": err}).Info("Failed to get logs!")
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(jitsilogs)
+	writeLogsResponse(w, jitsilogs, nextCursor)
 }
 
-// Query all logs earlier than a timestamp and export them as a CSV file
+// Export format accepted by streamLogs.
+type exportFormat string
+
+const (
+	exportFormatCSV    exportFormat = "csv"
+	exportFormatNDJSON exportFormat = "ndjson"
+)
+
+// Streams logs matching filter straight from the MongoDB cursor to w, in the
+// given format, without buffering the full result set in memory. Flushes
+// every BATCH_SIZE records and stops early if the client disconnects.
+func streamLogs(ctx context.Context, w http.ResponseWriter, filter bson.D, sort bson.D, format exportFormat) error {
+	tz, err := time.LoadLocation(TIMEZONE)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err}).Fatal("Failed to load TZ info")
+	}
+	collection := mongoClient.Database(DATABASE).Collection(COLLECTION)
+
+	optFind := options.Find().SetSort(sort).SetBatchSize(int32(BATCH_SIZE))
+	cursor, err := collection.Find(ctx, filter, optFind)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err}).Info("Error on finding the documents")
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	flusher, _ := w.(http.Flusher)
+	var csvWriter *csv.Writer
+	var jsonEncoder *json.Encoder
+	switch format {
+	case exportFormatCSV:
+		csvWriter = csv.NewWriter(w)
+		csvWriter.Comma = ';'
+		csvWriter.Write(cabecalhoCSV())
+	case exportFormatNDJSON:
+		jsonEncoder = json.NewEncoder(w)
+	}
+
+	var written int64
+	for cursor.Next(ctx) {
+		select {
+		case <-ctx.Done():
+			log.Info("Client disconnected, stopping export stream")
+			return ctx.Err()
+		default:
+		}
+
+		var jitsilog Jitsilog
+		if err := cursor.Decode(&jitsilog); err != nil {
+			log.WithFields(log.Fields{
+				"error": err}).Info("Error on decoding the document")
+			return err
+		}
+		if t, err := time.ParseInLocation(time.RFC3339, jitsilog.Timestamp, tz); err == nil {
+			jitsilog.Timestamp = t.In(tz).String()
+		} else {
+			jitsilog.Timestamp = "Falha no parser"
+		}
+
+		switch format {
+		case exportFormatCSV:
+			csvWriter.Write(jitsilog.registroCSV())
+		case exportFormatNDJSON:
+			jsonEncoder.Encode(&jitsilog)
+		}
+
+		written++
+		if written%BATCH_SIZE == 0 {
+			if csvWriter != nil {
+				csvWriter.Flush()
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if csvWriter != nil {
+		csvWriter.Flush()
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// Builds the $gte timestamp filter shared by the CSV/NDJSON export handlers,
+// combined with an optional DSL "filter" query param.
+func buildExportFilter(r *http.Request) (bson.D, error) {
+	dslFilter, err := parseFilterParam(r)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := r.URL.Query().Get("ts")
+	tsFilter := bson.D{{Key: "timestamp", Value: bson.D{{Key: "$gte", Value: timestamp}}}}
+	if len(dslFilter) == 0 {
+		return tsFilter, nil
+	}
+	return bson.D{{Key: "$and", Value: bson.A{tsFilter, dslFilter}}}, nil
+}
+
+// Query all logs earlier than a timestamp (optionally narrowed by the search
+// DSL's "filter" query param) and stream them as a CSV file.
 func searchAndExportAsCSV(w http.ResponseWriter, r *http.Request) {
-	queryParams := r.URL.Query()
-	timestamp := queryParams.Get("ts")
 	now := time.Now()
-
-	// preparing the response to output a csv file
 	w.Header().Set("Content-Type", "text/csv")
 	w.Header().Set("Content-Disposition",
 		"attachment; filename=jitsi-presence-logger."+now.Format(time.RFC3339)+".csv")
-	csvWriter := csv.NewWriter(w)
-	csvWriter.Comma = ';'
 
-	// querying database
-	filter := bson.D{{"timestamp", bson.D{{"$gte", timestamp}}}}
-	err, jitsilogs := findLogsFilter("0", filter, "0")
+	filter, err := buildExportFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sort := bson.D{{Key: "timestamp", Value: 1}}
+	if err := streamLogs(r.Context(), w, filter, sort, exportFormatCSV); err != nil {
+		log.WithFields(log.Fields{
+			"error": err}).Info("Failed to stream logs as CSV")
+	}
+}
 
-	// writing response
+// Query all logs earlier than a timestamp (optionally narrowed by the search
+// DSL's "filter" query param) and stream them as newline-delimited JSON.
+func searchAndExportAsNDJSON(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+
+	filter, err := buildExportFilter(r)
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sort := bson.D{{Key: "timestamp", Value: 1}}
+	if err := streamLogs(r.Context(), w, filter, sort, exportFormatNDJSON); err != nil {
 		log.WithFields(log.Fields{
-			"error": err}).Info("Failed to get logs!")
-		csvWriter.Write([]string{
-			"Ocorreu um erro ao realizar a requisição", err.Error(),
-		})
-	} else {
-		csvWriter.Write(cabecalhoCSV())
-		for _, log := range jitsilogs {
-			csvWriter.Write(log.registroCSV())
+			"error": err}).Info("Failed to stream logs as NDJSON")
+	}
+}
+
+// Request body accepted by searchDSLHandler.
+type SearchRequest struct {
+	Filter FilterGroup `json:"filter"`
+	Size   string      `json:"size,omitempty"`
+	After  string      `json:"after,omitempty"`
+}
+
+// Response body returned by searchDSLHandler.
+type SearchResponse struct {
+	Items         []*Jitsilog `json:"items"`
+	NextCursor    string      `json:"nextCursor,omitempty"`
+	TotalEstimate int64       `json:"totalEstimate"`
+}
+
+// Query logs using the structured filter DSL: a single endpoint that
+// combines field predicates with AND/OR instead of one endpoint per field.
+func searchDSLHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	var req SearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	predicateCount := 0
+	filter, err := buildDSLFilter(req.Filter, rawModeAllowed(r), &predicateCount)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	size := req.Size
+	if size == "" {
+		size = "20"
+	}
+	err, jitsilogs, nextCursor := findLogsFilter(ctx, size, filter, req.After)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err}).Info("Failed to run DSL search")
+		http.Error(w, "failed to run search", http.StatusInternalServerError)
+		return
+	}
+
+	collection := mongoClient.Database(DATABASE).Collection(COLLECTION)
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err}).Info("Failed to estimate total for DSL search")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SearchResponse{Items: jitsilogs, NextCursor: nextCursor, TotalEstimate: total})
+}
+
+// Aggregated attendance counters for a single bucket/group pair.
+type AttendanceRecord struct {
+	Bucket             string  `json:"bucket"`
+	Group              string  `json:"group"`
+	Joins              int64   `json:"joins"`
+	Leaves             int64   `json:"leaves"`
+	UniqueStudents     int64   `json:"uniqueStudents"`
+	AvgDurationSeconds float64 `json:"avgDurationSeconds"`
+}
+
+// Translates the "hour"|"day"|"week" bucket query arg into a $dateTrunc unit.
+func bucketUnit(bucket string) (string, error) {
+	switch bucket {
+	case "", "day":
+		return "day", nil
+	case "hour":
+		return "hour", nil
+	case "week":
+		return "week", nil
+	default:
+		return "", fmt.Errorf("unsupported bucket size: %s", bucket)
+	}
+}
+
+// Builds a $match+$group+$sort pipeline that buckets logs by time and by
+// groupField, pairing consecutive join/leave events per jid to estimate
+// dwell time.
+func buildAttendancePipeline(groupField string, from string, to string, bucket string) (bson.A, error) {
+	unit, err := bucketUnit(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	match := bson.D{}
+	timeRange := bson.D{}
+	if from != "" {
+		timeRange = append(timeRange, bson.E{Key: "$gte", Value: from})
+	}
+	if to != "" {
+		timeRange = append(timeRange, bson.E{Key: "$lte", Value: to})
+	}
+	if len(timeRange) > 0 {
+		match = append(match, bson.E{Key: "timestamp", Value: timeRange})
+	}
+
+	pipeline := bson.A{
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$addFields", Value: bson.D{
+			{Key: "ts", Value: bson.D{{Key: "$toDate", Value: "$timestamp"}}},
+		}}},
+		bson.D{{Key: "$addFields", Value: bson.D{
+			{Key: "bucketStart", Value: bson.D{{Key: "$dateTrunc", Value: bson.D{
+				{Key: "date", Value: "$ts"},
+				{Key: "unit", Value: unit},
+			}}}},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "jid", Value: 1}, {Key: "ts", Value: 1}}}},
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "bucket", Value: "$bucketStart"}, {Key: "group", Value: "$" + groupField}}},
+			{Key: "joins", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{bson.D{{Key: "$eq", Value: bson.A{"$action", "join"}}}, 1, 0}}}}}},
+			{Key: "leaves", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{bson.D{{Key: "$eq", Value: bson.A{"$action", "leave"}}}, 1, 0}}}}}},
+			{Key: "jids", Value: bson.D{{Key: "$addToSet", Value: "$jid"}}},
+			{Key: "events", Value: bson.D{{Key: "$push", Value: bson.D{
+				{Key: "jid", Value: "$jid"},
+				{Key: "action", Value: "$action"},
+				{Key: "ts", Value: "$ts"},
+			}}}},
+		}}},
+		bson.D{{Key: "$addFields", Value: bson.D{
+			{Key: "uniqueStudents", Value: bson.D{{Key: "$size", Value: "$jids"}}},
+			{Key: "durations", Value: durationPairingExpr()},
+		}}},
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "bucket", Value: bson.D{{Key: "$dateToString", Value: bson.D{
+				{Key: "date", Value: "$_id.bucket"},
+				{Key: "format", Value: "%Y-%m-%dT%H:%M:%SZ"},
+			}}}},
+			{Key: "group", Value: "$_id.group"},
+			{Key: "joins", Value: 1},
+			{Key: "leaves", Value: 1},
+			{Key: "uniqueStudents", Value: 1},
+			{Key: "avgDurationSeconds", Value: bson.D{{Key: "$ifNull", Value: bson.A{bson.D{{Key: "$avg", Value: "$durations"}}, 0}}}},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "bucket", Value: 1}, {Key: "group", Value: 1}}}},
+	}
+	return pipeline, nil
+}
+
+// Walks the (already sorted by jid, ts) event array of a group and, for
+// every join followed by a leave on the same jid, emits the dwell time in
+// seconds. Events that never see a matching leave are dropped.
+func durationPairingExpr() bson.D {
+	return bson.D{{Key: "$let", Value: bson.D{
+		{Key: "vars", Value: bson.D{
+			{Key: "folded", Value: bson.D{{Key: "$reduce", Value: bson.D{
+				{Key: "input", Value: "$events"},
+				{Key: "initialValue", Value: bson.D{
+					{Key: "pending", Value: bson.D{}},
+					{Key: "durations", Value: bson.A{}},
+				}},
+				{Key: "in", Value: bson.D{{Key: "$let", Value: bson.D{
+					{Key: "vars", Value: bson.D{
+						{Key: "isJoin", Value: bson.D{{Key: "$eq", Value: bson.A{"$$this.action", "join"}}}},
+						{Key: "isMatchedLeave", Value: bson.D{{Key: "$and", Value: bson.A{
+							bson.D{{Key: "$eq", Value: bson.A{"$$this.action", "leave"}}},
+							bson.D{{Key: "$ne", Value: bson.A{bson.D{{Key: "$type", Value: bson.D{{Key: "$getField", Value: bson.D{{Key: "field", Value: "$$this.jid"}, {Key: "input", Value: "$$value.pending"}}}}}}, "missing"}}},
+						}}}},
+					}},
+					{Key: "in", Value: bson.D{
+						{Key: "pending", Value: bson.D{{Key: "$cond", Value: bson.A{
+							"$$isJoin",
+							bson.D{{Key: "$mergeObjects", Value: bson.A{"$$value.pending", bson.D{{Key: "$$this.jid", Value: "$$this.ts"}}}}},
+							bson.D{{Key: "$cond", Value: bson.A{
+								"$$isMatchedLeave",
+								bson.D{{Key: "$unsetField", Value: bson.D{{Key: "field", Value: "$$this.jid"}, {Key: "input", Value: "$$value.pending"}}}},
+								"$$value.pending",
+							}}},
+						}}}},
+						{Key: "durations", Value: bson.D{{Key: "$cond", Value: bson.A{
+							"$$isMatchedLeave",
+							bson.D{{Key: "$concatArrays", Value: bson.A{
+								"$$value.durations",
+								bson.A{bson.D{{Key: "$dateDiff", Value: bson.D{
+									{Key: "startDate", Value: bson.D{{Key: "$getField", Value: bson.D{{Key: "field", Value: "$$this.jid"}, {Key: "input", Value: "$$value.pending"}}}}},
+									{Key: "endDate", Value: "$$this.ts"},
+									{Key: "unit", Value: "second"},
+								}}}},
+							}}},
+							"$$value.durations",
+						}}}},
+					}},
+				}}}},
+			}}}},
+		}},
+		{Key: "in", Value: "$$folded.durations"},
+	}}}
+}
+
+// Runs an attendance pipeline against the logs collection and decodes the
+// resulting records. ctx should carry a request-scoped timeout.
+func runAttendanceAggregation(ctx context.Context, pipeline bson.A) (error, []*AttendanceRecord) {
+	collection := mongoClient.Database(DATABASE).Collection(COLLECTION)
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err}).Info("Failed to run attendance aggregation")
+		return err, nil
+	}
+
+	var records []*AttendanceRecord
+	if err = cursor.All(ctx, &records); err != nil {
+		log.WithFields(log.Fields{
+			"error": err}).Info("Failed to decode attendance aggregation results")
+		return err, nil
+	}
+	return nil, records
+}
+
+// Shared handler for the /v1/analytics/attendance/* routes, parameterized
+// by which field to group by.
+func attendanceHandler(groupField string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := requestContext(r)
+		defer cancel()
+		queryParams := r.URL.Query()
+		pipeline, err := buildAttendancePipeline(groupField, queryParams.Get("from"), queryParams.Get("to"), queryParams.Get("bucket"))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err}).Info("Failed to build attendance pipeline")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
+		err, records := runAttendanceAggregation(ctx, pipeline)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err}).Info("Failed to get attendance aggregation!")
+			http.Error(w, "Failed to get attendance aggregation", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(records)
 	}
+}
+
+// Wraps http.ResponseWriter to capture the status code and byte count
+// written, for the request logging and metrics middleware below.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
 
-	csvWriter.Flush()
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Returns the route's path template (e.g. "/v1/logs/course"), or "unmatched"
+// when no route matched (404s). Used as a metric label instead of the raw
+// request path, which would otherwise give every probed/typo'd URL its own
+// permanently-retained Prometheus time series.
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return "unmatched"
+}
+
+// Records http_requests_total/http_request_duration_seconds and logs a
+// single structured entry per request (trace ID, route, params, status,
+// bytes, duration), replacing the combined-log-format handlers.LoggingHandler.
+func requestObservabilityMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		duration := time.Since(start).Seconds()
+		route := routeTemplate(r)
+		status := strconv.Itoa(rec.status)
+		httpRequestsTotal.WithLabelValues(route, r.Method, status).Inc()
+		httpRequestDuration.WithLabelValues(route, r.Method, status).Observe(duration)
+
+		fields := log.Fields{
+			"route":      route,
+			"path":       r.URL.Path,
+			"method":     r.Method,
+			"params":     r.URL.Query(),
+			"status":     rec.status,
+			"bytes":      rec.bytes,
+			"duration":   duration,
+			"remoteAddr": r.RemoteAddr,
+			"userAgent":  r.UserAgent(),
+		}
+		if spanCtx := trace.SpanContextFromContext(r.Context()); spanCtx.HasTraceID() {
+			fields["traceId"] = spanCtx.TraceID().String()
+		}
+		log.WithFields(fields).Info("Handled request")
+	})
 }
 
 func main() {
+	client, err := connectMongo()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err}).Fatal("Failed to connect to MongoDB!")
+	}
+	mongoClient = client
+	stopHealthLoop := make(chan struct{})
+	go mongoHealthLoop(mongoClient, stopHealthLoop)
+
 	router := mux.NewRouter()
+	router.Use(otelmux.Middleware("microservice-jitsi-log-view"))
+	router.Use(requestObservabilityMiddleware)
 	router.HandleFunc("/", defaultHandler).Methods(http.MethodGet)
 	router.HandleFunc("/healthcheck", checkHealth).Methods(http.MethodGet)
+	router.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
 	version := router.PathPrefix("/v1").Subrouter()
 	version.HandleFunc("/csv", searchAndExportAsCSV).Methods(http.MethodGet).Queries("ts", "{ts}")
+	version.HandleFunc("/ndjson", searchAndExportAsNDJSON).Methods(http.MethodGet).Queries("ts", "{ts}")
 	api := version.PathPrefix("/logs").Subrouter()
 	api.HandleFunc("/last", latestLogsHandler).Methods("GET")
 	api.HandleFunc("/course", searchCourseHandler).Methods("GET").Queries("id", "{id}")
 	api.HandleFunc("/class", searchClassHandler).Methods("GET").Queries("id", "{id}")
 	api.HandleFunc("/student", searchStudentHandler).Methods("GET").Queries("email", "{email}")
 	api.HandleFunc("/room", searchRoomHandler).Methods("GET").Queries("id", "{id}")
+	api.HandleFunc("/search", searchDSLHandler).Methods(http.MethodPost)
+	analytics := version.PathPrefix("/analytics").Subrouter()
+	analytics.HandleFunc("/attendance/course", attendanceHandler("curso")).Methods("GET")
+	analytics.HandleFunc("/attendance/room", attendanceHandler("sala")).Methods("GET")
+	analytics.HandleFunc("/attendance/student", attendanceHandler("email")).Methods("GET")
 	http.Handle("/", router)
-	loggedRouter := handlers.LoggingHandler(os.Stdout, router)
-	log.Fatal(http.ListenAndServe(PORT, handlers.CORS()(loggedRouter)))
+	server := &http.Server{Addr: PORT, Handler: handlers.CORS()(router)}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.WithFields(log.Fields{
+				"error": err}).Fatal("HTTP server failed")
+		}
+	}()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	<-sigCh
+	log.Info("Shutting down gracefully...")
+	close(stopHealthLoop)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.WithFields(log.Fields{
+			"error": err}).Info("Error shutting down HTTP server")
+	}
+	if err := mongoClient.Disconnect(shutdownCtx); err != nil {
+		log.WithFields(log.Fields{
+			"error": err}).Info("Error disconnecting from MongoDB")
+	}
 }
@@ -0,0 +1,70 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tcmongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Demonstrates the throughput the shared, pooled Mongo client sustains under
+// concurrent requests. The per-call connectMongo/Disconnect path this change
+// replaced is gone from the tree, so there is nothing left to benchmark it
+// against directly; this instead pins down the pooled client's concurrent
+// find throughput so a future regression (e.g. shrinking the pool back down)
+// shows up as a benchmark drop.
+func BenchmarkFindLogsFilterPooled(b *testing.B) {
+	ctx := context.Background()
+
+	container, err := tcmongodb.Run(ctx, "mongo:6")
+	if err != nil {
+		b.Fatalf("failed to start mongo container: %v", err)
+	}
+	defer container.Terminate(ctx)
+
+	endpoint, err := container.ConnectionString(ctx)
+	if err != nil {
+		b.Fatalf("failed to get connection string: %v", err)
+	}
+
+	URI_MONGODB = endpoint
+	client, err := connectMongo()
+	if err != nil {
+		b.Fatalf("failed to connect to mongo: %v", err)
+	}
+	defer client.Disconnect(ctx)
+
+	mongoClient = client
+	DATABASE = "jitsi_bench"
+	COLLECTION = "logs"
+	BATCH_SIZE = 500
+
+	collection := client.Database(DATABASE).Collection(COLLECTION)
+	base := time.Date(2026, 7, 1, 10, 0, 0, 0, time.UTC)
+	docs := make([]interface{}, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		docs = append(docs, Jitsilog{
+			Jid:       "jid-1",
+			Timestamp: base.Add(time.Duration(i) * time.Second).Format(time.RFC3339),
+			Action:    "join",
+		})
+	}
+	if _, err := collection.InsertMany(ctx, docs); err != nil {
+		b.Fatalf("failed to seed documents: %v", err)
+	}
+
+	filter := bson.D{}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err, _, _ := findLogsFilter(ctx, "50", filter, ""); err != nil {
+				b.Fatalf("findLogsFilter: %v", err)
+			}
+		}
+	})
+}